@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"tinyauth/internal/config"
+	"tinyauth/internal/service"
+	"tinyauth/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// AppControllerConfig is the static configuration needed to wire the forward-auth check.
+type AppControllerConfig struct {
+	AppURL         string
+	RedirectPolicy utils.RedirectPolicy
+}
+
+// AppController is the forward-auth endpoint a reverse proxy (traefik, nginx, ...) calls
+// before forwarding a request to an app, mirroring the ForwardAuth contract: 2xx lets the
+// request through, anything else is returned to the proxy as the response. It resolves the
+// app being accessed through AppDiscovery instead of a concrete backend, so whichever
+// backend APP_DISCOVERY_BACKEND selects (Docker, Kubernetes, file) is actually consulted.
+type AppController struct {
+	config    AppControllerConfig
+	router    *gin.RouterGroup
+	discovery service.AppDiscovery
+}
+
+func NewAppController(config AppControllerConfig, router *gin.RouterGroup, discovery service.AppDiscovery) *AppController {
+	return &AppController{
+		config:    config,
+		router:    router,
+		discovery: discovery,
+	}
+}
+
+func (controller *AppController) SetupRoutes() {
+	controller.router.GET("/auth", controller.authHandler)
+}
+
+// authHandler reads the forwarded request's original URL off the X-Forwarded-* headers the
+// proxy sets, looks up the target app via AppDiscovery.GetApp, and either lets the request
+// through, rejects it, or sends the browser to the login page with the original URL as the
+// post-login redirect target.
+func (controller *AppController) authHandler(c *gin.Context) {
+	proto := c.Request.Header.Get("X-Forwarded-Proto")
+	if proto == "" {
+		proto = "https"
+	}
+
+	host := c.Request.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": "Missing X-Forwarded-Host"})
+		return
+	}
+
+	originalURL := proto + "://" + host + c.Request.Header.Get("X-Forwarded-Uri")
+
+	domain := host
+	if hostOnly, _, err := net.SplitHostPort(host); err == nil {
+		domain = hostOnly
+	}
+
+	app, err := controller.discovery.GetApp(domain)
+	if err != nil {
+		log.Error().Err(err).Str("domain", domain).Msg("Failed to look up app")
+		c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": "Internal server error"})
+		return
+	}
+
+	userContext, err := utils.GetContext(c)
+	if err != nil || !userContext.IsLoggedIn {
+		controller.redirectToLogin(c, originalURL)
+		return
+	}
+
+	if !appAccessAllowed(app, userContext) {
+		c.JSON(http.StatusForbidden, gin.H{"status": http.StatusForbidden, "message": "Forbidden"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// redirectToLogin sends the browser to the login page with originalURL as the post-login
+// redirect target, first checking it against RedirectPolicy so a forged X-Forwarded-Host/Uri
+// can't turn this into an open redirect.
+func (controller *AppController) redirectToLogin(c *gin.Context, originalURL string) {
+	if !utils.IsRedirectSafe(originalURL, controller.config.RedirectPolicy) {
+		log.Warn().Str("url", originalURL).Msg("Refusing to redirect to untrusted app URL")
+		c.JSON(http.StatusForbidden, gin.H{"status": http.StatusForbidden, "message": "Untrusted redirect target"})
+		return
+	}
+
+	location := controller.config.AppURL + "/login?redirect_uri=" + url.QueryEscape(originalURL)
+	c.Redirect(http.StatusTemporaryRedirect, location)
+}
+
+// appAccessAllowed reports whether user may access app. An app with no AllowedUsers or
+// AllowedGroups configured is open to anyone who is logged in.
+func appAccessAllowed(app config.App, user config.UserContext) bool {
+	if len(app.Config.AllowedUsers) == 0 && len(app.Config.AllowedGroups) == 0 {
+		return true
+	}
+
+	for _, allowed := range app.Config.AllowedUsers {
+		if allowed == user.Username {
+			return true
+		}
+	}
+
+	for _, group := range strings.Split(user.OAuthGroups, ",") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		for _, allowed := range app.Config.AllowedGroups {
+			if group == allowed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
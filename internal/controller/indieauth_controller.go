@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+
+	"tinyauth/internal/config"
+	"tinyauth/internal/service"
+	"tinyauth/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// IndieAuthControllerConfig is the static configuration needed to wire the IndieAuth routes.
+type IndieAuthControllerConfig struct {
+	AppURL string
+}
+
+// IndieAuthController implements the IndieAuth spec (a small profile on top of OAuth2 +
+// PKCE where client_id and me are URLs), so a tinyauth login can be used to sign into any
+// IndieAuth-consuming site without running a second server.
+type IndieAuthController struct {
+	config     IndieAuthControllerConfig
+	router     *gin.RouterGroup
+	service    *service.IndieAuthService
+	lookupUser func(username string) (config.UserContext, bool)
+}
+
+func NewIndieAuthController(config IndieAuthControllerConfig, router *gin.RouterGroup, indieAuthService *service.IndieAuthService, lookupUser func(username string) (config.UserContext, bool)) *IndieAuthController {
+	return &IndieAuthController{
+		config:     config,
+		router:     router,
+		service:    indieAuthService,
+		lookupUser: lookupUser,
+	}
+}
+
+func (controller *IndieAuthController) SetupRoutes() {
+	controller.router.GET("/indieauth/authorize", controller.authorizeHandler)
+	controller.router.POST("/indieauth/authorize", controller.authorizeHandler)
+	controller.router.POST("/indieauth/token", controller.tokenHandler)
+}
+
+// authorizeHandler serves the h-app discovery response on a GET without
+// response_type=code. Any other request (a GET with response_type=code, or a POST) issues
+// a PKCE-bound authorization code for the logged in user.
+func (controller *IndieAuthController) authorizeHandler(c *gin.Context) {
+	if c.Request.Method == http.MethodGet && c.Query("response_type") != "code" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(`<div class="h-app"><a href="`+controller.config.AppURL+`" class="u-url p-name">tinyauth</a></div>`))
+		return
+	}
+
+	userContext, err := utils.GetContext(c)
+	if err != nil || !userContext.IsLoggedIn {
+		redirect := controller.config.AppURL + c.Request.URL.String()
+		c.Redirect(http.StatusTemporaryRedirect, controller.config.AppURL+"/login?redirect_uri="+url.QueryEscape(redirect))
+		return
+	}
+
+	me := c.Query("me")
+	clientID := c.Query("client_id")
+	redirectURI := c.Query("redirect_uri")
+	scope := c.Query("scope")
+	codeChallenge := c.Query("code_challenge")
+	codeChallengeMethod := c.Query("code_challenge_method")
+
+	code, err := controller.service.Authorize(me, clientID, redirectURI, scope, codeChallenge, codeChallengeMethod, userContext)
+	if err != nil {
+		log.Debug().Err(err).Str("me", me).Msg("IndieAuth authorize request rejected")
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": err.Error()})
+		return
+	}
+
+	state := c.Query("state")
+	location := redirectURI + "?code=" + code
+	if state != "" {
+		location += "&state=" + state
+	}
+
+	c.Redirect(http.StatusFound, location)
+}
+
+type indieAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	ClientID     string `form:"client_id"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+}
+
+func (controller *IndieAuthController) tokenHandler(c *gin.Context) {
+	var req indieAuthTokenRequest
+
+	if err := c.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": "invalid request"})
+		return
+	}
+
+	if req.GrantType != "authorization_code" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": "unsupported_grant_type"})
+		return
+	}
+
+	result, err := controller.service.Exchange(req.Code, req.ClientID, req.RedirectURI, req.CodeVerifier, controller.lookupUser)
+	if err != nil {
+		log.Debug().Err(err).Str("client_id", req.ClientID).Msg("IndieAuth token exchange rejected")
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"me":           result.Me,
+		"profile":      result.Profile,
+		"access_token": result.AccessToken,
+		"scope":        result.Scope,
+	})
+}
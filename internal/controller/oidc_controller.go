@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"tinyauth/internal/config"
+	"tinyauth/internal/service/oidc"
+	"tinyauth/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// OidcControllerConfig is the static configuration needed to wire the OIDC routes, mirroring
+// the RootDomain-style config structs used by the other controllers.
+type OidcControllerConfig struct {
+	AppURL string
+}
+
+// OidcController exposes tinyauth as an OIDC/OAuth2 provider so downstream apps that speak
+// OIDC natively can authenticate directly instead of only via forward-auth.
+type OidcController struct {
+	config     OidcControllerConfig
+	router     *gin.RouterGroup
+	service    *oidc.Service
+	lookupUser func(username string) (config.UserContext, bool)
+}
+
+func NewOidcController(config OidcControllerConfig, router *gin.RouterGroup, service *oidc.Service, lookupUser func(username string) (config.UserContext, bool)) *OidcController {
+	return &OidcController{
+		config:     config,
+		router:     router,
+		service:    service,
+		lookupUser: lookupUser,
+	}
+}
+
+func (controller *OidcController) SetupRoutes() {
+	controller.router.GET("/.well-known/openid-configuration", controller.discoveryHandler)
+	controller.router.GET("/oidc/jwks", controller.jwksHandler)
+	controller.router.GET("/oidc/authorize", controller.authorizeHandler)
+	controller.router.POST("/oidc/token", controller.tokenHandler)
+	controller.router.GET("/oidc/userinfo", controller.userInfoHandler)
+}
+
+func (controller *OidcController) discoveryHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, controller.service.Discovery())
+}
+
+func (controller *OidcController) jwksHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"keys": controller.service.JWKS()})
+}
+
+// authorizeHandler issues an authorization code for the already logged in session. If the
+// user isn't logged in yet, it redirects to the login controller with the original
+// authorize request preserved as the post-login redirect target, the same pattern the
+// forward-auth flow already uses.
+func (controller *OidcController) authorizeHandler(c *gin.Context) {
+	userContext, err := utils.GetContext(c)
+	if err != nil || !userContext.IsLoggedIn {
+		redirect := controller.config.AppURL + c.Request.URL.String()
+		c.Redirect(http.StatusTemporaryRedirect, controller.config.AppURL+"/login?redirect_uri="+url.QueryEscape(redirect))
+		return
+	}
+
+	clientID := c.Query("client_id")
+	redirectURI := c.Query("redirect_uri")
+	scope := strings.Fields(c.Query("scope"))
+
+	code, err := controller.service.Authorize(clientID, redirectURI, scope, userContext)
+	if err != nil {
+		log.Debug().Err(err).Str("client_id", clientID).Msg("OIDC authorize request rejected")
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": err.Error()})
+		return
+	}
+
+	state := c.Query("state")
+	location := redirectURI + "?code=" + code
+	if state != "" {
+		location += "&state=" + state
+	}
+
+	c.Redirect(http.StatusFound, location)
+}
+
+type tokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+func (controller *OidcController) tokenHandler(c *gin.Context) {
+	var req tokenRequest
+
+	if err := c.Bind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": "invalid request"})
+		return
+	}
+
+	if req.GrantType != "authorization_code" {
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": "unsupported_grant_type"})
+		return
+	}
+
+	result, err := controller.service.Exchange(req.ClientID, req.ClientSecret, req.Code, controller.lookupUser)
+
+	if err != nil {
+		log.Debug().Err(err).Str("client_id", req.ClientID).Msg("OIDC token exchange rejected")
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": result.AccessToken,
+		"id_token":     result.IDToken,
+		"token_type":   "Bearer",
+		"expires_in":   result.ExpiresIn,
+		"scope":        result.Scope,
+	})
+}
+
+// userInfoHandler authenticates purely via the Authorization: Bearer <access_token> header
+// minted by tokenHandler, per the OIDC spec - it must not fall back to the tinyauth session
+// cookie, since relying parties call this from a backend that has no such session.
+func (controller *OidcController) userInfoHandler(c *gin.Context) {
+	token, ok := bearerToken(c)
+	if !ok {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.JSON(http.StatusUnauthorized, gin.H{"status": http.StatusUnauthorized, "message": "invalid token"})
+		return
+	}
+
+	claims, err := controller.service.UserInfo(token, controller.lookupUser)
+	if err != nil {
+		c.Header("WWW-Authenticate", "Bearer")
+		c.JSON(http.StatusUnauthorized, gin.H{"status": http.StatusUnauthorized, "message": "invalid token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, claims)
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	const prefix = "Bearer "
+
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+
+	token := strings.TrimPrefix(header, prefix)
+	if token == "" {
+		return "", false
+	}
+
+	return token, true
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"net/http"
+
+	"tinyauth/internal/service"
+	"tinyauth/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// WebAuthnControllerConfig mirrors the cookie-issuing fields AuthServiceConfig already uses,
+// since a successful passkey assertion has to set the same kind of session cookie TOTP does.
+type WebAuthnControllerConfig struct {
+	RootDomain   string
+	SecureCookie bool
+}
+
+// WebAuthnController exposes passkey/FIDO2 registration and login alongside the existing
+// TOTP second factor. Login offers WebAuthn first when the user has registered
+// credentials, falling back to TOTP otherwise.
+type WebAuthnController struct {
+	config      WebAuthnControllerConfig
+	router      *gin.RouterGroup
+	service     *service.WebAuthnService
+	rateLimiter gin.HandlerFunc
+}
+
+func NewWebAuthnController(config WebAuthnControllerConfig, router *gin.RouterGroup, webauthnService *service.WebAuthnService, rateLimiter gin.HandlerFunc) *WebAuthnController {
+	return &WebAuthnController{
+		config:      config,
+		router:      router,
+		service:     webauthnService,
+		rateLimiter: rateLimiter,
+	}
+}
+
+func (controller *WebAuthnController) SetupRoutes() {
+	group := controller.router.Group("/user/webauthn")
+
+	// Same rate limiting as /api/user/totp, since both are ways to complete login.
+	group.Use(controller.rateLimiter)
+
+	group.GET("/available", controller.availableHandler)
+	group.POST("/register/begin", controller.registerBeginHandler)
+	group.POST("/register/finish", controller.registerFinishHandler)
+	group.POST("/login/begin", controller.loginBeginHandler)
+	group.POST("/login/finish", controller.loginFinishHandler)
+}
+
+// availableHandler reports whether username has any registered passkeys, so the login page
+// can decide whether to offer WebAuthn before falling back to TOTP.
+func (controller *WebAuthnController) availableHandler(c *gin.Context) {
+	username := c.Query("username")
+
+	available, err := controller.service.HasCredentials(username)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to check webauthn credentials")
+		c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"available": available})
+}
+
+func (controller *WebAuthnController) registerBeginHandler(c *gin.Context) {
+	userContext, err := utils.GetContext(c)
+	if err != nil || !userContext.IsLoggedIn {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": http.StatusUnauthorized, "message": "Unauthorized"})
+		return
+	}
+
+	options, err := controller.service.BeginRegistration(userContext.Username)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to begin webauthn registration")
+		c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+func (controller *WebAuthnController) registerFinishHandler(c *gin.Context) {
+	userContext, err := utils.GetContext(c)
+	if err != nil || !userContext.IsLoggedIn {
+		c.JSON(http.StatusUnauthorized, gin.H{"status": http.StatusUnauthorized, "message": "Unauthorized"})
+		return
+	}
+
+	if err := controller.service.FinishRegistration(userContext.Username, c.Request); err != nil {
+		log.Debug().Err(err).Msg("Failed to finish webauthn registration")
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": "Invalid registration response"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": http.StatusOK, "message": "Passkey registered"})
+}
+
+type webAuthnLoginBeginRequest struct {
+	// PendingToken is minted by the password-verification step via
+	// WebAuthnService.CreatePendingToken, mirroring how a TOTP-pending login is set after the
+	// password check - the ceremony is bound to that prior step, not a bare username.
+	PendingToken string `json:"pendingToken" binding:"required"`
+}
+
+func (controller *WebAuthnController) loginBeginHandler(c *gin.Context) {
+	var req webAuthnLoginBeginRequest
+
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"status": http.StatusBadRequest, "message": "Bad request"})
+		return
+	}
+
+	options, err := controller.service.BeginLogin(req.PendingToken)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to begin webauthn login")
+		c.JSON(http.StatusUnauthorized, gin.H{"status": http.StatusUnauthorized, "message": "No passkeys registered"})
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// loginFinishHandler is expected to be mounted before the auth context middleware, the same
+// way /api/user/login and /api/user/totp are, since the caller isn't logged in yet. It still
+// requires the pendingToken issued after the password check, not a bare username, before it
+// will mint a real session off the assertion.
+func (controller *WebAuthnController) loginFinishHandler(c *gin.Context) {
+	pendingToken := c.Query("pendingToken")
+
+	username, err := controller.service.FinishLogin(pendingToken, c.Request)
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to finish webauthn login")
+		c.JSON(http.StatusUnauthorized, gin.H{"status": http.StatusUnauthorized, "message": "Invalid passkey assertion"})
+		return
+	}
+
+	token, expiry, err := controller.service.CreateSessionToken(username)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("Failed to create session after webauthn login")
+		c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": "Internal server error"})
+		return
+	}
+
+	c.SetCookie("tinyauth-session", token, int(expiry.Seconds()), "/", controller.config.RootDomain, controller.config.SecureCookie, true)
+
+	c.JSON(http.StatusOK, gin.H{"status": http.StatusOK, "message": "Logged in"})
+}
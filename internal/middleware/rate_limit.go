@@ -0,0 +1,45 @@
+// Package middleware holds gin middleware shared across controllers.
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit returns a gin middleware that allows at most max requests per client IP within
+// window, the same limiter shape tinyauth already applies to /api/user/totp.
+func RateLimit(max int, window time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	hits := make(map[string][]time.Time)
+
+	return func(c *gin.Context) {
+		key := c.ClientIP()
+		now := time.Now()
+
+		mu.Lock()
+
+		recent := hits[key][:0]
+		for _, hit := range hits[key] {
+			if now.Sub(hit) < window {
+				recent = append(recent, hit)
+			}
+		}
+
+		if len(recent) >= max {
+			mu.Unlock()
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"status":  http.StatusTooManyRequests,
+				"message": "Too many requests",
+			})
+			return
+		}
+
+		hits[key] = append(recent, now)
+		mu.Unlock()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,161 @@
+// Package server assembles tinyauth's services and controllers into a runnable gin
+// engine. It is the single place new routes get mounted, so nothing added to
+// internal/controller ends up unreachable in the running binary.
+package server
+
+import (
+	"time"
+
+	"tinyauth/internal/config"
+	"tinyauth/internal/controller"
+	"tinyauth/internal/middleware"
+	"tinyauth/internal/service"
+	"tinyauth/internal/service/oidc"
+	"tinyauth/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Config is the top-level, env-driven configuration needed to build the server. It
+// gathers the per-feature configs the individual services already expect.
+type Config struct {
+	RootDomain   string
+	AppURL       string
+	SecureCookie bool
+
+	SessionSecret     string
+	SessionExpiry     int
+	SessionCookieName string
+
+	Oidc      oidc.ServiceConfig
+	WebAuthn  service.WebAuthnServiceConfig
+	IndieAuth service.IndieAuthServiceConfig
+
+	AppDiscoveryBackend string
+	FileDiscovery       service.FileDiscoveryConfig
+	KubernetesDiscovery service.KubernetesDiscoveryConfig
+
+	RedirectPolicy utils.RedirectPolicy
+}
+
+// Dependencies are the already-constructed, longer-lived services the router wires
+// controllers to. lookupUser resolves a username to the config.UserContext claims the new
+// identity-provider endpoints need, the same way AuthService already resolves a session
+// into a UserContext for the rest of tinyauth.
+type Dependencies struct {
+	Database   *gorm.DB
+	LookupUser func(username string) (config.UserContext, bool)
+}
+
+// NewRouter builds the gin engine and mounts every controller's routes on it, including the
+// forward-auth check that resolves the app being accessed through AppDiscovery.GetApp. It
+// also returns the running AppDiscovery backend so callers can shut it down.
+func NewRouter(cfg Config, deps Dependencies) (*gin.Engine, service.AppDiscovery, error) {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	api := router.Group("/api")
+
+	if err := mountOidc(router, api, cfg, deps); err != nil {
+		return nil, nil, err
+	}
+
+	if err := mountWebAuthn(api, cfg, deps); err != nil {
+		return nil, nil, err
+	}
+
+	if err := mountIndieAuth(router, cfg, deps); err != nil {
+		return nil, nil, err
+	}
+
+	appDiscovery, err := mountAppDiscovery(api, cfg, deps)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return router, appDiscovery, nil
+}
+
+func mountOidc(router *gin.Engine, api *gin.RouterGroup, cfg Config, deps Dependencies) error {
+	oidcService := oidc.NewService(cfg.Oidc, deps.Database)
+	if err := oidcService.Init(); err != nil {
+		return err
+	}
+
+	controller.NewOidcController(
+		controller.OidcControllerConfig{AppURL: cfg.AppURL},
+		router.Group("/"),
+		oidcService,
+		deps.LookupUser,
+	).SetupRoutes()
+
+	return nil
+}
+
+// mountWebAuthn wires passkey registration/login under /api/user/webauthn, rate limited the
+// same way /api/user/totp already is.
+func mountWebAuthn(api *gin.RouterGroup, cfg Config, deps Dependencies) error {
+	webauthnConfig := cfg.WebAuthn
+	webauthnConfig.SessionSecret = cfg.SessionSecret
+	webauthnConfig.SessionExpiry = cfg.SessionExpiry
+	webauthnConfig.SessionCookieName = cfg.SessionCookieName
+
+	webauthnService := service.NewWebAuthnService(webauthnConfig, deps.Database)
+	if err := webauthnService.Init(); err != nil {
+		return err
+	}
+
+	controller.NewWebAuthnController(
+		controller.WebAuthnControllerConfig{
+			RootDomain:   cfg.RootDomain,
+			SecureCookie: cfg.SecureCookie,
+		},
+		api,
+		webauthnService,
+		middleware.RateLimit(10, time.Minute),
+	).SetupRoutes()
+
+	return nil
+}
+
+// mountAppDiscovery starts the configured AppDiscovery backend and wires AppController's
+// forward-auth check to it, so the backend selected via APP_DISCOVERY_BACKEND is actually
+// consulted on the request path instead of just running in the background.
+func mountAppDiscovery(api *gin.RouterGroup, cfg Config, deps Dependencies) (service.AppDiscovery, error) {
+	appDiscovery, err := service.NewAppDiscovery(cfg.AppDiscoveryBackend, cfg.FileDiscovery, cfg.KubernetesDiscovery)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appDiscovery.Init(); err != nil {
+		return nil, err
+	}
+
+	controller.NewAppController(
+		controller.AppControllerConfig{
+			AppURL:         cfg.AppURL,
+			RedirectPolicy: cfg.RedirectPolicy,
+		},
+		api,
+		appDiscovery,
+	).SetupRoutes()
+
+	return appDiscovery, nil
+}
+
+func mountIndieAuth(router *gin.Engine, cfg Config, deps Dependencies) error {
+	indieAuthService := service.NewIndieAuthService(cfg.IndieAuth, deps.Database)
+	if err := indieAuthService.Init(); err != nil {
+		return err
+	}
+
+	controller.NewIndieAuthController(
+		controller.IndieAuthControllerConfig{AppURL: cfg.AppURL},
+		router.Group("/"),
+		indieAuthService,
+		deps.LookupUser,
+	).SetupRoutes()
+
+	return nil
+}
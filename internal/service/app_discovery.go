@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+
+	"tinyauth/internal/config"
+)
+
+// AppEvent is emitted by an AppDiscovery backend whenever an app's config changes, so
+// callers that want push updates (rather than polling GetApp) can react to them.
+type AppEvent struct {
+	Domain  string
+	App     config.App
+	Removed bool
+}
+
+// AppDiscovery is how tinyauth learns per-app config (domain, allowed users/groups, ...).
+// DockerService was previously the only source; implementing this interface lets the auth
+// controller call AppDiscovery.GetApp instead of reaching for a concrete backend, so
+// Kubernetes, file-based and any future backend can be swapped in via APP_DISCOVERY_BACKEND.
+type AppDiscovery interface {
+	Init() error
+	GetApp(domain string) (config.App, error)
+	Watch() <-chan AppEvent
+}
+
+const (
+	AppDiscoveryBackendDocker     = "docker"
+	AppDiscoveryBackendKubernetes = "kubernetes"
+	AppDiscoveryBackendFile       = "file"
+)
+
+// NewAppDiscovery selects and constructs the configured AppDiscovery backend. It does not
+// call Init - callers are expected to do that the same way they Init every other service.
+func NewAppDiscovery(backend string, fileConfig FileDiscoveryConfig, kubernetesConfig KubernetesDiscoveryConfig) (AppDiscovery, error) {
+	switch backend {
+	case "", AppDiscoveryBackendDocker:
+		return NewDockerService(), nil
+	case AppDiscoveryBackendKubernetes:
+		return NewKubernetesDiscoveryService(kubernetesConfig), nil
+	case AppDiscoveryBackendFile:
+		return NewFileDiscoveryService(fileConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown APP_DISCOVERY_BACKEND %q", backend)
+	}
+}
@@ -2,104 +2,352 @@ package service
 
 import (
 	"context"
-	"strings"
+	"sync"
+	"time"
+
 	"tinyauth/internal/config"
 	"tinyauth/internal/utils/decoders"
 
-	container "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 	"github.com/rs/zerolog/log"
 )
 
+// watchedActions are the container lifecycle events that can change the set of labels
+// DockerService has cached and therefore require a cache update.
+var watchedActions = map[string]bool{
+	"start":   true,
+	"die":     true,
+	"destroy": true,
+	"update":  true,
+	"rename":  true,
+}
+
+const (
+	eventsMinBackoff = time.Second
+	eventsMaxBackoff = 30 * time.Second
+)
+
+// dockerClient is the subset of the Docker API used by DockerService, extracted as an
+// interface so tests can drive the cache with a fake implementation instead of a daemon.
+type dockerClient interface {
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error)
+}
+
 type DockerService struct {
-	client      *client.Client
+	client      dockerClient
 	context     context.Context
+	cancel      context.CancelFunc
 	isConnected bool
+
+	mu            sync.RWMutex
+	cache         map[string]config.App
+	containerKeys map[string][]string
+
+	events chan AppEvent
 }
 
+// NewDockerService constructs the Docker AppDiscovery backend. It implements the
+// AppDiscovery interface alongside its older GetLabels-based API so existing callers keep
+// working while the auth controller migrates to AppDiscovery.GetApp.
 func NewDockerService() *DockerService {
-	return &DockerService{}
+	return &DockerService{
+		cache:         make(map[string]config.App),
+		containerKeys: make(map[string][]string),
+		events:        make(chan AppEvent, 32),
+	}
 }
 
 func (docker *DockerService) Init() error {
-	client, err := client.NewClientWithOpts(client.FromEnv)
+	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		return err
 	}
 
-	ctx := context.Background()
-	client.NegotiateAPIVersion(ctx)
+	ctx, cancel := context.WithCancel(context.Background())
+	cli.NegotiateAPIVersion(ctx)
 
-	docker.client = client
+	docker.client = cli
 	docker.context = ctx
+	docker.cancel = cancel
+	docker.cache = make(map[string]config.App)
+	docker.containerKeys = make(map[string][]string)
 
-	_, err = docker.client.Ping(docker.context)
+	_, err = cli.Ping(ctx)
 
 	if err != nil {
 		log.Debug().Err(err).Msg("Docker not connected")
 		docker.isConnected = false
 		docker.client = nil
 		docker.context = nil
+		cancel()
 		return nil
 	}
 
 	docker.isConnected = true
 	log.Debug().Msg("Docker connected")
 
+	if err := docker.resync(); err != nil {
+		return err
+	}
+
+	go docker.watchEvents()
+
 	return nil
 }
 
-func (docker *DockerService) getContainers() ([]container.Summary, error) {
+// Close stops the event watcher. It is a no-op if Docker was never connected.
+func (docker *DockerService) Close() {
+	if docker.cancel != nil {
+		docker.cancel()
+	}
+}
+
+// resync rebuilds the cache from scratch by listing and inspecting every container. It is
+// used for the initial population and after the event stream drops and reconnects, so the
+// cache can't permanently drift from reality if an event is missed mid-outage.
+func (docker *DockerService) resync() error {
 	containers, err := docker.client.ContainerList(docker.context, container.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	cache := make(map[string]config.App)
+	containerKeys := make(map[string][]string)
+
+	for _, ctr := range containers {
+		entries, err := docker.entriesForContainer(ctr.ID)
+		if err != nil {
+			log.Debug().Err(err).Str("id", ctr.ID).Msg("Failed to inspect container during resync")
+			continue
+		}
+
+		keys := make([]string, 0, len(entries))
+
+		for key, app := range entries {
+			cache[key] = app
+			keys = append(keys, key)
+		}
+
+		containerKeys[ctr.ID] = keys
+	}
+
+	docker.mu.Lock()
+	docker.cache = cache
+	docker.containerKeys = containerKeys
+	docker.mu.Unlock()
+
+	log.Debug().Int("containers", len(containers)).Msg("Resynced docker label cache")
+
+	return nil
+}
+
+// entriesForContainer inspects a single container and returns its cache entries, keyed by
+// both the app's configured domain and its app name.
+func (docker *DockerService) entriesForContainer(containerID string) (map[string]config.App, error) {
+	inspect, err := docker.client.ContainerInspect(docker.context, containerID)
 	if err != nil {
 		return nil, err
 	}
-	return containers, nil
+
+	labels, err := decoders.DecodeLabels(inspect.Config.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]config.App, len(labels.Apps)*2)
+
+	for appName, appLabels := range labels.Apps {
+		if appLabels.Config.Domain != "" {
+			entries[appLabels.Config.Domain] = appLabels
+		}
+		if appName != "" {
+			entries[appName] = appLabels
+		}
+	}
+
+	return entries, nil
 }
 
-func (docker *DockerService) inspectContainer(containerId string) (container.InspectResponse, error) {
-	inspect, err := docker.client.ContainerInspect(docker.context, containerId)
+// updateContainer re-inspects a single container and replaces whatever entries it
+// previously contributed to the cache with the current ones.
+func (docker *DockerService) updateContainer(containerID string) {
+	entries, err := docker.entriesForContainer(containerID)
 	if err != nil {
-		return container.InspectResponse{}, err
+		log.Debug().Err(err).Str("id", containerID).Msg("Failed to inspect container for cache update")
+		return
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	docker.mu.Lock()
+
+	for _, key := range docker.containerKeys[containerID] {
+		delete(docker.cache, key)
+	}
+
+	for key, app := range entries {
+		docker.cache[key] = app
+	}
+
+	docker.containerKeys[containerID] = keys
+
+	docker.mu.Unlock()
+
+	for key, app := range entries {
+		docker.publish(AppEvent{Domain: key, App: app})
 	}
-	return inspect, nil
 }
 
-func (docker *DockerService) GetLabels(appDomain string) (config.App, error) {
-	if !docker.isConnected {
-		log.Debug().Msg("Docker not connected, returning empty labels")
-		return config.App{}, nil
+// removeContainer drops whatever entries a container contributed to the cache, used when
+// the container is gone and can no longer be inspected.
+func (docker *DockerService) removeContainer(containerID string) {
+	docker.mu.Lock()
+
+	keys := docker.containerKeys[containerID]
+	for _, key := range keys {
+		delete(docker.cache, key)
 	}
 
-	containers, err := docker.getContainers()
-	if err != nil {
-		return config.App{}, err
+	delete(docker.containerKeys, containerID)
+
+	docker.mu.Unlock()
+
+	for _, key := range keys {
+		docker.publish(AppEvent{Domain: key, Removed: true})
 	}
+}
 
-	for _, ctr := range containers {
-		inspect, err := docker.inspectContainer(ctr.ID)
-		if err != nil {
-			return config.App{}, err
+// publish pushes an AppEvent to the Watch channel without blocking the caller if nobody is
+// currently reading it.
+func (docker *DockerService) publish(event AppEvent) {
+	select {
+	case docker.events <- event:
+	default:
+		log.Debug().Str("domain", event.Domain).Msg("Dropped app discovery event, no watcher reading fast enough")
+	}
+}
+
+// watchEvents subscribes to the Docker events stream and keeps the cache fresh as
+// containers start, stop or change. If the stream drops it backs off exponentially,
+// reconnects and does a full resync before resuming, so a missed event can't leave the
+// cache stale forever.
+func (docker *DockerService) watchEvents() {
+	backoff := eventsMinBackoff
+
+	for {
+		if docker.context.Err() != nil {
+			return
 		}
 
-		labels, err := decoders.DecodeLabels(inspect.Config.Labels)
-		if err != nil {
-			return config.App{}, err
+		filterArgs := filters.NewArgs()
+		filterArgs.Add("type", "container")
+		for action := range watchedActions {
+			filterArgs.Add("event", action)
 		}
 
-		for appName, appLabels := range labels.Apps {
-			if appLabels.Config.Domain == appDomain {
-				log.Debug().Str("id", inspect.ID).Str("name", inspect.Name).Msg("Found matching container by domain")
-				return appLabels, nil
+		msgs, errs := docker.client.Events(docker.context, events.ListOptions{Filters: filterArgs})
+
+		log.Debug().Msg("Subscribed to docker events")
+		subscribedAt := time.Now()
+
+		streamErr := docker.consumeEvents(msgs, errs)
+		if streamErr == nil {
+			return
+		}
+
+		// A stream that stayed up longer than the maximum backoff counts as healthy, so the
+		// next reconnect starts again from the minimum delay.
+		if time.Since(subscribedAt) > eventsMaxBackoff {
+			backoff = eventsMinBackoff
+		}
+
+		log.Warn().Err(streamErr).Dur("backoff", backoff).Msg("Docker events stream dropped, reconnecting")
+
+		select {
+		case <-docker.context.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := docker.resync(); err != nil {
+			log.Warn().Err(err).Msg("Failed to resync docker label cache after reconnect")
+		}
+
+		backoff *= 2
+		if backoff > eventsMaxBackoff {
+			backoff = eventsMaxBackoff
+		}
+	}
+}
+
+// consumeEvents drains the event stream until it closes or errors. It returns nil only
+// when the service context was cancelled, and an error otherwise so the caller reconnects.
+func (docker *DockerService) consumeEvents(msgs <-chan events.Message, errs <-chan error) error {
+	for {
+		select {
+		case <-docker.context.Done():
+			return nil
+		case err, ok := <-errs:
+			if !ok {
+				return context.Canceled
+			}
+			return err
+		case msg, ok := <-msgs:
+			if !ok {
+				return context.Canceled
+			}
+
+			if !watchedActions[string(msg.Action)] {
+				continue
 			}
 
-			if strings.TrimPrefix(inspect.Name, "/") == appName {
-				log.Debug().Str("id", inspect.ID).Str("name", inspect.Name).Msg("Found matching container by app name")
-				return appLabels, nil
+			if msg.Action == "die" || msg.Action == "destroy" {
+				docker.removeContainer(msg.Actor.ID)
+				continue
 			}
+
+			docker.updateContainer(msg.Actor.ID)
 		}
 	}
+}
+
+// GetLabels looks up the cached config.App for a domain. The cache is kept up to date by
+// watchEvents, so this is an O(1) map lookup instead of a Docker API round-trip.
+func (docker *DockerService) GetLabels(appDomain string) (config.App, error) {
+	if !docker.isConnected {
+		log.Debug().Msg("Docker not connected, returning empty labels")
+		return config.App{}, nil
+	}
+
+	docker.mu.RLock()
+	defer docker.mu.RUnlock()
+
+	if app, ok := docker.cache[appDomain]; ok {
+		log.Debug().Str("domain", appDomain).Msg("Found matching app in cache")
+		return app, nil
+	}
+
+	log.Debug().Str("domain", appDomain).Msg("No matching app found in cache")
 
-	log.Debug().Msg("No matching container found, returning empty labels")
 	return config.App{}, nil
 }
+
+// GetApp implements AppDiscovery. It's equivalent to GetLabels; the two names exist side
+// by side until every caller has moved to the AppDiscovery interface.
+func (docker *DockerService) GetApp(domain string) (config.App, error) {
+	return docker.GetLabels(domain)
+}
+
+// Watch implements AppDiscovery, streaming an AppEvent for every container add/update/removal
+// handled by watchEvents.
+func (docker *DockerService) Watch() <-chan AppEvent {
+	return docker.events
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"gotest.tools/v3/assert"
+)
+
+// fakeDockerClient is an in-memory stand-in for the Docker API used to exercise
+// DockerService without a real daemon.
+type fakeDockerClient struct {
+	containers []container.Summary
+	inspects   map[string]container.InspectResponse
+
+	msgs chan events.Message
+	errs chan error
+}
+
+func newFakeDockerClient() *fakeDockerClient {
+	return &fakeDockerClient{
+		inspects: make(map[string]container.InspectResponse),
+		msgs:     make(chan events.Message, 8),
+		errs:     make(chan error, 1),
+	}
+}
+
+func (f *fakeDockerClient) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	return f.containers, nil
+}
+
+func (f *fakeDockerClient) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	inspect, ok := f.inspects[containerID]
+	if !ok {
+		return container.InspectResponse{}, errors.New("no such container")
+	}
+	return inspect, nil
+}
+
+func (f *fakeDockerClient) Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error) {
+	return f.msgs, f.errs
+}
+
+func containerLabels(appName string, domain string) map[string]string {
+	apps := map[string]any{
+		appName: map[string]any{
+			"config": map[string]any{
+				"domain": domain,
+			},
+		},
+	}
+
+	encoded := make(map[string]string, len(apps))
+	for name, cfg := range apps {
+		raw, _ := json.Marshal(cfg)
+		encoded["tinyauth.apps."+name] = string(raw)
+	}
+
+	return encoded
+}
+
+func newDockerServiceWithClient(client dockerClient) *DockerService {
+	docker := NewDockerService()
+	docker.client = client
+	docker.context = context.Background()
+	docker.cancel = func() {}
+	docker.isConnected = true
+	return docker
+}
+
+func TestDockerServiceResyncAndLookup(t *testing.T) {
+	client := newFakeDockerClient()
+	client.containers = []container.Summary{{ID: "c1", Names: []string{"/app1"}}}
+	client.inspects["c1"] = container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "c1", Name: "/app1"},
+		Config:            &container.Config{Labels: containerLabels("app1", "app1.example.com")},
+	}
+
+	docker := newDockerServiceWithClient(client)
+
+	assert.NilError(t, docker.resync())
+
+	app, err := docker.GetLabels("app1.example.com")
+	assert.NilError(t, err)
+	assert.Equal(t, app.Config.Domain, "app1.example.com")
+
+	app, err = docker.GetLabels("app1")
+	assert.NilError(t, err)
+	assert.Equal(t, app.Config.Domain, "app1.example.com")
+}
+
+func TestDockerServiceUpdateOnStartEvent(t *testing.T) {
+	client := newFakeDockerClient()
+	docker := newDockerServiceWithClient(client)
+	assert.NilError(t, docker.resync())
+
+	client.inspects["c2"] = container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "c2", Name: "/app2"},
+		Config:            &container.Config{Labels: containerLabels("app2", "app2.example.com")},
+	}
+
+	docker.updateContainer("c2")
+
+	app, err := docker.GetLabels("app2.example.com")
+	assert.NilError(t, err)
+	assert.Equal(t, app.Config.Domain, "app2.example.com")
+}
+
+func TestDockerServiceRemoveOnDieEvent(t *testing.T) {
+	client := newFakeDockerClient()
+	docker := newDockerServiceWithClient(client)
+
+	client.inspects["c3"] = container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{ID: "c3", Name: "/app3"},
+		Config:            &container.Config{Labels: containerLabels("app3", "app3.example.com")},
+	}
+	docker.updateContainer("c3")
+
+	_, err := docker.GetLabels("app3.example.com")
+	assert.NilError(t, err)
+
+	docker.removeContainer("c3")
+
+	app, err := docker.GetLabels("app3.example.com")
+	assert.NilError(t, err)
+	assert.Equal(t, app.Config.Domain, "")
+}
+
+func TestDockerServiceGetLabelsWhenDisconnected(t *testing.T) {
+	docker := NewDockerService()
+	docker.isConnected = false
+
+	app, err := docker.GetLabels("app1.example.com")
+	assert.NilError(t, err)
+	assert.Equal(t, app.Config.Domain, "")
+}
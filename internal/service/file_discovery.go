@@ -0,0 +1,165 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"tinyauth/internal/config"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// FileDiscoveryConfig is the static configuration for the file-based AppDiscovery backend.
+type FileDiscoveryConfig struct {
+	Path string // path to a YAML file listing apps, reloaded on change
+}
+
+// fileApp is a single entry in the apps file, decoded into a config.App.
+type fileApp struct {
+	Name          string   `yaml:"name"`
+	Domain        string   `yaml:"domain"`
+	AllowedGroups []string `yaml:"allowedGroups"`
+	AllowedUsers  []string `yaml:"allowedUsers"`
+}
+
+type fileAppsDocument struct {
+	Apps []fileApp `yaml:"apps"`
+}
+
+// FileDiscoveryService implements AppDiscovery by reading a YAML file of apps and
+// reloading it whenever it changes on disk, for bare-metal deployments that have neither a
+// Docker socket nor a Kubernetes API available.
+type FileDiscoveryService struct {
+	config FileDiscoveryConfig
+
+	mu    sync.RWMutex
+	cache map[string]config.App
+
+	events chan AppEvent
+}
+
+func NewFileDiscoveryService(config FileDiscoveryConfig) *FileDiscoveryService {
+	return &FileDiscoveryService{
+		config: config,
+		cache:  make(map[string]config.App),
+		events: make(chan AppEvent, 32),
+	}
+}
+
+func (service *FileDiscoveryService) Init() error {
+	if err := service.reload(); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// Watch the containing directory rather than the file itself: an atomic-replace write
+	// (temp file + rename, which ConfigMap mounts and most editors use) swaps out the
+	// inode fsnotify was watching, which would otherwise silently stop delivering events
+	// after the first such edit.
+	if err := watcher.Add(filepath.Dir(service.config.Path)); err != nil {
+		return err
+	}
+
+	go service.watch(watcher)
+
+	return nil
+}
+
+func (service *FileDiscoveryService) watch(watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	name := filepath.Base(service.config.Path)
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(event.Name) != name {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			if err := service.reload(); err != nil {
+				log.Warn().Err(err).Str("path", service.config.Path).Msg("Failed to reload app discovery file")
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn().Err(err).Msg("App discovery file watcher error")
+		}
+	}
+}
+
+// reload re-reads the apps file from disk and replaces the cache, publishing a removal for
+// any app no longer present and an update for every app in the new file.
+func (service *FileDiscoveryService) reload() error {
+	raw, err := os.ReadFile(service.config.Path)
+	if err != nil {
+		return err
+	}
+
+	var document fileAppsDocument
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+
+	cache := make(map[string]config.App, len(document.Apps))
+	for _, app := range document.Apps {
+		cache[app.Domain] = config.App{
+			Config: config.AppConfig{
+				Domain:        app.Domain,
+				AllowedGroups: app.AllowedGroups,
+				AllowedUsers:  app.AllowedUsers,
+			},
+		}
+	}
+
+	service.mu.Lock()
+	previous := service.cache
+	service.cache = cache
+	service.mu.Unlock()
+
+	for domain := range previous {
+		if _, ok := cache[domain]; !ok {
+			service.publish(AppEvent{Domain: domain, Removed: true})
+		}
+	}
+
+	for domain, app := range cache {
+		service.publish(AppEvent{Domain: domain, App: app})
+	}
+
+	log.Debug().Int("apps", len(cache)).Str("path", service.config.Path).Msg("Reloaded app discovery file")
+
+	return nil
+}
+
+func (service *FileDiscoveryService) publish(event AppEvent) {
+	select {
+	case service.events <- event:
+	default:
+		log.Debug().Str("domain", event.Domain).Msg("Dropped app discovery event, no watcher reading fast enough")
+	}
+}
+
+func (service *FileDiscoveryService) GetApp(domain string) (config.App, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	return service.cache[domain], nil
+}
+
+func (service *FileDiscoveryService) Watch() <-chan AppEvent {
+	return service.events
+}
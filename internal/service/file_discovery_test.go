@@ -0,0 +1,29 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFileDiscoveryServiceReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apps.yaml")
+
+	assert.NilError(t, os.WriteFile(path, []byte(`
+apps:
+  - name: app1
+    domain: app1.example.com
+    allowedGroups: ["admins"]
+`), 0o644))
+
+	service := NewFileDiscoveryService(FileDiscoveryConfig{Path: path})
+
+	assert.NilError(t, service.reload())
+
+	app, err := service.GetApp("app1.example.com")
+	assert.NilError(t, err)
+	assert.Equal(t, app.Config.Domain, "app1.example.com")
+	assert.DeepEqual(t, app.Config.AllowedGroups, []string{"admins"})
+}
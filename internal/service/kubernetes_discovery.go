@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"tinyauth/internal/config"
+
+	"github.com/rs/zerolog/log"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Annotation keys tinyauth reads off Ingress resources to build a config.App, mirroring the
+// fields configured via Docker labels today.
+const (
+	ingressAnnotationDomain        = "tinyauth.app/domain"
+	ingressAnnotationAllowedGroups = "tinyauth.app/allowed-groups"
+	ingressAnnotationAllowedUsers  = "tinyauth.app/allowed-users"
+)
+
+// KubernetesDiscoveryConfig is the static configuration for the Kubernetes AppDiscovery
+// backend.
+type KubernetesDiscoveryConfig struct {
+	Namespace string // empty means watch Ingresses across all namespaces
+}
+
+// KubernetesDiscoveryService implements AppDiscovery by watching Ingress resources and
+// reading tinyauth config from their tinyauth.app/* annotations, so tinyauth can run
+// without a Docker socket mount on Kubernetes.
+type KubernetesDiscoveryService struct {
+	config    KubernetesDiscoveryConfig
+	clientset kubernetes.Interface
+
+	mu    sync.RWMutex
+	cache map[string]config.App
+
+	events chan AppEvent
+}
+
+func NewKubernetesDiscoveryService(config KubernetesDiscoveryConfig) *KubernetesDiscoveryService {
+	return &KubernetesDiscoveryService{
+		config: config,
+		cache:  make(map[string]config.App),
+		events: make(chan AppEvent, 32),
+	}
+}
+
+func (service *KubernetesDiscoveryService) Init() error {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	service.clientset = clientset
+
+	informer := cache.NewSharedInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return service.clientset.NetworkingV1().Ingresses(service.config.Namespace).List(context.Background(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return service.clientset.NetworkingV1().Ingresses(service.config.Namespace).Watch(context.Background(), options)
+			},
+		},
+		&networkingv1.Ingress{},
+		0,
+	)
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { service.onIngress(obj) },
+		UpdateFunc: func(_ any, obj any) { service.onIngress(obj) },
+		DeleteFunc: func(obj any) { service.onIngressDelete(obj) },
+	})
+
+	go informer.Run(context.Background().Done())
+
+	log.Debug().Str("namespace", service.config.Namespace).Msg("Watching Kubernetes Ingresses for tinyauth config")
+
+	return nil
+}
+
+func (service *KubernetesDiscoveryService) onIngress(obj any) {
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		return
+	}
+
+	domain := ingress.Annotations[ingressAnnotationDomain]
+	if domain == "" {
+		return
+	}
+
+	app := config.App{
+		Config: config.AppConfig{
+			Domain: domain,
+		},
+	}
+
+	if groups := ingress.Annotations[ingressAnnotationAllowedGroups]; groups != "" {
+		app.Config.AllowedGroups = strings.Split(groups, ",")
+	}
+
+	if users := ingress.Annotations[ingressAnnotationAllowedUsers]; users != "" {
+		app.Config.AllowedUsers = strings.Split(users, ",")
+	}
+
+	service.mu.Lock()
+	service.cache[domain] = app
+	service.mu.Unlock()
+
+	service.publish(AppEvent{Domain: domain, App: app})
+}
+
+func (service *KubernetesDiscoveryService) onIngressDelete(obj any) {
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		return
+	}
+
+	domain := ingress.Annotations[ingressAnnotationDomain]
+	if domain == "" {
+		return
+	}
+
+	service.mu.Lock()
+	delete(service.cache, domain)
+	service.mu.Unlock()
+
+	service.publish(AppEvent{Domain: domain, Removed: true})
+}
+
+func (service *KubernetesDiscoveryService) publish(event AppEvent) {
+	select {
+	case service.events <- event:
+	default:
+		log.Debug().Str("domain", event.Domain).Msg("Dropped app discovery event, no watcher reading fast enough")
+	}
+}
+
+func (service *KubernetesDiscoveryService) GetApp(domain string) (config.App, error) {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	return service.cache[domain], nil
+}
+
+func (service *KubernetesDiscoveryService) Watch() <-chan AppEvent {
+	return service.events
+}
@@ -0,0 +1,85 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Client is a statically configured OIDC relying party, analogous to how config.User
+// entries are configured today.
+type Client struct {
+	ID            string   `json:"id"`
+	SecretHash    string   `json:"secretHash"`
+	RedirectURIs  []string `json:"redirectUris"`
+	Scopes        []string `json:"scopes"`
+	AllowedGroups []string `json:"allowedGroups"`
+}
+
+// ParseClients decodes the OIDC_CLIENTS env var, a JSON array of Client objects, the same
+// way the rest of tinyauth's static config is supplied.
+func ParseClients(raw string) ([]Client, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var clients []Client
+
+	if err := json.Unmarshal([]byte(raw), &clients); err != nil {
+		return nil, err
+	}
+
+	for _, client := range clients {
+		if client.ID == "" {
+			return nil, errors.New("oidc client missing id")
+		}
+		if len(client.RedirectURIs) == 0 {
+			return nil, errors.New("oidc client " + client.ID + " has no redirect_uris")
+		}
+	}
+
+	return clients, nil
+}
+
+func (client Client) redirectAllowed(redirectURI string) bool {
+	for _, allowed := range client.RedirectURIs {
+		if allowed == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+func (client Client) scopeAllowed(scopes []string) bool {
+	if len(client.Scopes) == 0 {
+		return true
+	}
+
+	allowed := make(map[string]bool, len(client.Scopes))
+	for _, scope := range client.Scopes {
+		allowed[scope] = true
+	}
+
+	for _, scope := range scopes {
+		if !allowed[scope] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (client Client) groupAllowed(groups []string) bool {
+	if len(client.AllowedGroups) == 0 {
+		return true
+	}
+
+	for _, group := range groups {
+		for _, allowed := range client.AllowedGroups {
+			if group == allowed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,20 @@
+package oidc
+
+// Discovery builds the /.well-known/openid-configuration document for this provider.
+func (service *Service) Discovery() map[string]any {
+	issuer := service.config.Issuer
+
+	return map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oidc/authorize",
+		"token_endpoint":                        issuer + "/oidc/token",
+		"userinfo_endpoint":                     issuer + "/oidc/userinfo",
+		"jwks_uri":                              issuer + "/oidc/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "groups"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_post"},
+		"claims_supported":                      []string{"sub", "preferred_username", "email", "name", "groups"},
+	}
+}
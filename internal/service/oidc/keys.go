@@ -0,0 +1,184 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// SigningKey is a persisted RS256 key pair. Rotated keys are kept around (but no longer
+// used to sign) until ExpiresAt so in-flight ID tokens and the JWKS document stay valid.
+type SigningKey struct {
+	KID        string `gorm:"primaryKey"`
+	PrivatePEM string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+
+	key *rsa.PrivateKey
+}
+
+// keyStore manages the signing key rotation schedule, persisting keys through the
+// database service the same way the rest of tinyauth's durable state is stored.
+type keyStore struct {
+	database *gorm.DB
+	rotation time.Duration
+
+	mu      sync.RWMutex
+	active  *SigningKey
+	retired []*SigningKey
+}
+
+func newKeyStore(database *gorm.DB, rotation time.Duration) *keyStore {
+	return &keyStore{database: database, rotation: rotation}
+}
+
+// init loads any persisted keys, generating the first one on a fresh database, and
+// rotates immediately if the active key is already past its rotation schedule.
+func (store *keyStore) init() error {
+	if err := store.database.AutoMigrate(&SigningKey{}); err != nil {
+		return err
+	}
+
+	var keys []SigningKey
+	if err := store.database.Order("created_at asc").Find(&keys).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Pick the active key (the most recently created, still-valid one) first, then file
+	// everything else under retired - so the active key never also ends up in retired.
+	var loadedKeys []*SigningKey
+	for i := range keys {
+		loaded := &keys[i]
+		parsed, err := parsePrivateKey(loaded.PrivatePEM)
+		if err != nil {
+			continue
+		}
+		loaded.key = parsed
+
+		if !loaded.ExpiresAt.After(now) {
+			continue
+		}
+
+		loadedKeys = append(loadedKeys, loaded)
+
+		if store.active == nil || loaded.CreatedAt.After(store.active.CreatedAt) {
+			store.active = loaded
+		}
+	}
+
+	for _, loaded := range loadedKeys {
+		if loaded.KID == store.active.KID {
+			continue
+		}
+		store.retired = append(store.retired, loaded)
+	}
+
+	if store.active == nil || now.Sub(store.active.CreatedAt) >= store.rotation {
+		return store.rotateLocked()
+	}
+
+	return nil
+}
+
+// rotate generates a new signing key, retires the previous one (kept in JWKS until its
+// tokens can no longer be valid) and persists both.
+func (store *keyStore) rotate() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return store.rotateLocked()
+}
+
+func (store *keyStore) rotateLocked() error {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	key := &SigningKey{
+		KID:        base64.RawURLEncoding.EncodeToString([]byte(now.Format(time.RFC3339Nano))),
+		PrivatePEM: encodePrivateKey(privateKey),
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(2 * store.rotation),
+		key:        privateKey,
+	}
+
+	if err := store.database.Create(key).Error; err != nil {
+		return err
+	}
+
+	if store.active != nil {
+		store.retired = append(store.retired, store.active)
+	}
+	store.active = key
+
+	return nil
+}
+
+func (store *keyStore) signingKey() *SigningKey {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.active
+}
+
+// publicKeys returns every key still valid for JWKS publication, active first.
+func (store *keyStore) publicKeys() []*SigningKey {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(store.retired)+1)
+	if store.active != nil {
+		keys = append(keys, store.active)
+	}
+
+	now := time.Now()
+	for _, key := range store.retired {
+		if key.ExpiresAt.After(now) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (store *keyStore) findKey(kid string) *rsa.PrivateKey {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if store.active != nil && store.active.KID == kid {
+		return store.active.key
+	}
+	for _, key := range store.retired {
+		if key.KID == kid {
+			return key.key
+		}
+	}
+	return nil
+}
+
+func encodePrivateKey(key *rsa.PrivateKey) string {
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	return string(pem.EncodeToMemory(block))
+}
+
+func parsePrivateKey(encoded string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
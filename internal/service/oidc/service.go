@@ -0,0 +1,351 @@
+// Package oidc implements tinyauth as an OIDC/OAuth2 identity provider, so downstream
+// apps that speak OIDC natively (Grafana, Argo, ...) can authenticate against tinyauth
+// directly instead of only via forward-auth.
+package oidc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"time"
+
+	"tinyauth/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownClient      = errors.New("unknown client_id")
+	ErrRedirectURI        = errors.New("redirect_uri not allowed for this client")
+	ErrScope              = errors.New("scope not allowed for this client")
+	ErrGroup              = errors.New("user is not in an allowed group for this client")
+	ErrUnknownCode        = errors.New("unknown or expired authorization code")
+	ErrClientSecret       = errors.New("invalid client secret")
+	ErrCodeAlreadyUsed    = errors.New("authorization code already redeemed")
+	ErrInvalidAccessToken = errors.New("invalid or expired access token")
+)
+
+const (
+	authCodeTTL    = 60 * time.Second
+	accessTokenTTL = time.Hour
+)
+
+// authCode is a single-use authorization code, persisted so a token exchange can land on
+// a different tinyauth instance behind a load balancer.
+type authCode struct {
+	Code      string `gorm:"primaryKey"`
+	ClientID  string
+	Username  string
+	Scope     string
+	Redeemed  bool
+	ExpiresAt time.Time
+}
+
+// accessToken is a persisted bearer token minted alongside an ID token, so /oidc/userinfo
+// can validate it instead of trusting the caller's browser session - a relying party's
+// backend calls userinfo with the token directly and has no tinyauth session cookie.
+type accessToken struct {
+	Token     string `gorm:"primaryKey"`
+	Username  string
+	ClientID  string
+	ExpiresAt time.Time
+}
+
+// ServiceConfig is the static configuration for the OIDC provider, supplied the same way
+// as the rest of tinyauth's env-driven config.
+type ServiceConfig struct {
+	Issuer      string
+	Clients     []Client
+	KeyRotation time.Duration
+}
+
+type Service struct {
+	config ServiceConfig
+	keys   *keyStore
+}
+
+func NewService(config ServiceConfig, database *gorm.DB) *Service {
+	if config.KeyRotation <= 0 {
+		config.KeyRotation = 7 * 24 * time.Hour
+	}
+
+	return &Service{
+		config: config,
+		keys:   newKeyStore(database, config.KeyRotation),
+	}
+}
+
+func (service *Service) Init() error {
+	if err := service.keys.database.AutoMigrate(&authCode{}, &accessToken{}); err != nil {
+		return err
+	}
+
+	if err := service.keys.init(); err != nil {
+		return err
+	}
+
+	go service.rotateKeysPeriodically()
+
+	return nil
+}
+
+// rotateKeysPeriodically rotates the signing key on the configured schedule for the
+// lifetime of the process. Old keys stay published in JWKS until they expire, so tokens
+// already handed out keep verifying across the rotation.
+func (service *Service) rotateKeysPeriodically() {
+	ticker := time.NewTicker(service.config.KeyRotation)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		service.rotateKeysIfDue()
+	}
+}
+
+func (service *Service) client(clientID string) (Client, bool) {
+	for _, client := range service.config.Clients {
+		if client.ID == clientID {
+			return client, true
+		}
+	}
+	return Client{}, false
+}
+
+// Authorize validates an /oidc/authorize request against the configured client and, if it
+// checks out, mints a short-lived single-use authorization code for the already logged in
+// user. The caller is responsible for redirecting to the login controller first if the
+// user isn't logged in yet.
+func (service *Service) Authorize(clientID string, redirectURI string, scope []string, user config.UserContext) (string, error) {
+	client, ok := service.client(clientID)
+	if !ok {
+		return "", ErrUnknownClient
+	}
+
+	if !client.redirectAllowed(redirectURI) {
+		return "", ErrRedirectURI
+	}
+
+	if !client.scopeAllowed(scope) {
+		return "", ErrScope
+	}
+
+	if !client.groupAllowed(splitGroups(user.OAuthGroups)) {
+		return "", ErrGroup
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	entry := authCode{
+		Code:      code,
+		ClientID:  clientID,
+		Username:  user.Username,
+		Scope:     joinScope(scope),
+		ExpiresAt: time.Now().Add(authCodeTTL),
+	}
+
+	if err := service.keys.database.Create(&entry).Error; err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// TokenResult is what the /oidc/token endpoint returns on a successful exchange.
+type TokenResult struct {
+	IDToken     string
+	AccessToken string
+	ExpiresIn   int64
+	Scope       string
+}
+
+// Exchange redeems an authorization code for an ID token and access token. users looks up
+// the config.UserContext backing the code's username, mirroring how the rest of tinyauth
+// resolves a session into claims.
+func (service *Service) Exchange(clientID string, clientSecret string, code string, users func(username string) (config.UserContext, bool)) (TokenResult, error) {
+	client, ok := service.client(clientID)
+	if !ok {
+		return TokenResult{}, ErrUnknownClient
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(clientSecret)); err != nil {
+		return TokenResult{}, ErrClientSecret
+	}
+
+	var entry authCode
+	if err := service.keys.database.First(&entry, "code = ?", code).Error; err != nil {
+		return TokenResult{}, ErrUnknownCode
+	}
+
+	if entry.Redeemed {
+		return TokenResult{}, ErrCodeAlreadyUsed
+	}
+
+	if time.Now().After(entry.ExpiresAt) {
+		return TokenResult{}, ErrUnknownCode
+	}
+
+	if entry.ClientID != clientID {
+		return TokenResult{}, ErrUnknownCode
+	}
+
+	if err := service.keys.database.Model(&entry).Update("redeemed", true).Error; err != nil {
+		return TokenResult{}, err
+	}
+
+	user, ok := users(entry.Username)
+	if !ok {
+		return TokenResult{}, ErrUnknownCode
+	}
+
+	idToken, expiresIn, err := service.signIDToken(clientID, user)
+	if err != nil {
+		return TokenResult{}, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return TokenResult{}, err
+	}
+
+	if err := service.keys.database.Create(&accessToken{
+		Token:     token,
+		Username:  user.Username,
+		ClientID:  clientID,
+		ExpiresAt: time.Now().Add(accessTokenTTL),
+	}).Error; err != nil {
+		return TokenResult{}, err
+	}
+
+	return TokenResult{
+		IDToken:     idToken,
+		AccessToken: token,
+		ExpiresIn:   expiresIn,
+		Scope:       entry.Scope,
+	}, nil
+}
+
+func (service *Service) signIDToken(clientID string, user config.UserContext) (string, int64, error) {
+	signingKey := service.keys.signingKey()
+	if signingKey == nil {
+		return "", 0, errors.New("no signing key available")
+	}
+
+	now := time.Now()
+	expiresIn := int64(accessTokenTTL.Seconds())
+
+	claims := jwt.MapClaims{
+		"iss":                service.config.Issuer,
+		"sub":                user.Username,
+		"aud":                clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(accessTokenTTL).Unix(),
+		"preferred_username": user.Username,
+		"name":               user.Name,
+		"email":              user.Email,
+		"groups":             splitGroups(user.OAuthGroups),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = signingKey.KID
+
+	signed, err := token.SignedString(signingKey.key)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return signed, expiresIn, nil
+}
+
+// UserInfo validates the bearer access token presented to /oidc/userinfo and returns its
+// claims. It does not use the tinyauth session cookie at all - a relying party's backend
+// calls this endpoint with only the access token, no browser session.
+func (service *Service) UserInfo(token string, users func(username string) (config.UserContext, bool)) (map[string]any, error) {
+	var entry accessToken
+	if err := service.keys.database.First(&entry, "token = ?", token).Error; err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	if time.Now().After(entry.ExpiresAt) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	user, ok := users(entry.Username)
+	if !ok {
+		return nil, ErrInvalidAccessToken
+	}
+
+	return map[string]any{
+		"sub":                user.Username,
+		"preferred_username": user.Username,
+		"name":               user.Name,
+		"email":              user.Email,
+		"groups":             splitGroups(user.OAuthGroups),
+	}, nil
+}
+
+// JWKS returns the public half of every still-valid signing key, for /oidc/jwks.
+func (service *Service) JWKS() []map[string]any {
+	keys := service.keys.publicKeys()
+	jwks := make([]map[string]any, 0, len(keys))
+
+	for _, key := range keys {
+		jwks = append(jwks, map[string]any{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": key.KID,
+			"n":   base64.RawURLEncoding.EncodeToString(key.key.PublicKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.key.PublicKey.E)).Bytes()),
+		})
+	}
+
+	return jwks
+}
+
+func (service *Service) rotateKeysIfDue() {
+	if err := service.keys.rotate(); err != nil {
+		log.Warn().Err(err).Msg("Failed to rotate OIDC signing key")
+	}
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func splitGroups(groups string) []string {
+	if groups == "" {
+		return nil
+	}
+
+	var result []string
+	start := 0
+	for i, r := range groups {
+		if r == ',' {
+			result = append(result, groups[start:i])
+			start = i + 1
+		}
+	}
+	result = append(result, groups[start:])
+	return result
+}
+
+func joinScope(scope []string) string {
+	joined := ""
+	for i, s := range scope {
+		if i > 0 {
+			joined += " "
+		}
+		joined += s
+	}
+	return joined
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebAuthnUserNotFound   = errors.New("user not found")
+	ErrWebAuthnSessionExpired = errors.New("webauthn ceremony expired or not started")
+	ErrWebAuthnPendingInvalid = errors.New("webauthn pending token invalid or expired")
+)
+
+// webAuthnPendingExpiry bounds how long a pending token minted after a successful password
+// check stays usable to complete a WebAuthn ceremony, the same way a TOTP-pending login has
+// to be finished soon after the password step or started over.
+const webAuthnPendingExpiry = 5 * time.Minute
+
+// webAuthnPendingPurpose tags a pending token's claims so it can't be swapped for a full
+// session token minted by CreateSessionToken, even though both are signed with the same
+// secret.
+const webAuthnPendingPurpose = "webauthn-pending"
+
+// WebAuthnCredential is a registered FIDO2 authenticator/passkey, persisted alongside the
+// rest of tinyauth's durable state.
+type WebAuthnCredential struct {
+	ID              uint `gorm:"primaryKey"`
+	Username        string
+	CredentialID    []byte
+	PublicKey       []byte
+	AttestationType string
+	Transports      string
+	SignCount       uint32
+	UserHandle      []byte
+	CreatedAt       time.Time
+}
+
+// WebAuthnServiceConfig is the static configuration needed to stand up the relying party,
+// mirroring the RPDisplayName/RPID/RPOrigins fields github.com/go-webauthn/webauthn expects,
+// plus the session-issuing fields AuthServiceConfig already uses for the TOTP flow.
+type WebAuthnServiceConfig struct {
+	RPDisplayName string
+	RPID          string
+	RPOrigins     []string
+
+	SessionSecret     string
+	SessionExpiry     int
+	SessionCookieName string
+}
+
+// WebAuthnService wraps github.com/go-webauthn/webauthn to offer passkeys/FIDO2
+// authenticators as an alternative or additional second factor alongside TOTP.
+type WebAuthnService struct {
+	config   WebAuthnServiceConfig
+	webauthn *webauthn.WebAuthn
+	database *gorm.DB
+
+	// sessionsMu guards sessions, which is read and written from concurrently handled
+	// registration/login requests from different users.
+	sessionsMu sync.Mutex
+	// sessions holds the in-flight registration/login ceremony data keyed by username,
+	// the same way TOTP keeps a pending state between the two request round-trips.
+	sessions map[string]*webauthn.SessionData
+}
+
+func NewWebAuthnService(config WebAuthnServiceConfig, database *gorm.DB) *WebAuthnService {
+	return &WebAuthnService{
+		config:   config,
+		database: database,
+		sessions: make(map[string]*webauthn.SessionData),
+	}
+}
+
+func (service *WebAuthnService) Init() error {
+	wa, err := webauthn.New(&webauthn.Config{
+		RPDisplayName: service.config.RPDisplayName,
+		RPID:          service.config.RPID,
+		RPOrigins:     service.config.RPOrigins,
+	})
+	if err != nil {
+		return err
+	}
+
+	service.webauthn = wa
+
+	return service.database.AutoMigrate(&WebAuthnCredential{})
+}
+
+// webAuthnUser adapts a tinyauth username and its credentials to webauthn.User.
+type webAuthnUser struct {
+	username    string
+	credentials []WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte          { return []byte(u.username) }
+func (u *webAuthnUser) WebAuthnName() string        { return u.username }
+func (u *webAuthnUser) WebAuthnDisplayName() string { return u.username }
+func (u *webAuthnUser) WebAuthnIcon() string        { return "" }
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+
+	for _, stored := range u.credentials {
+		credentials = append(credentials, webauthn.Credential{
+			ID:              stored.CredentialID,
+			PublicKey:       stored.PublicKey,
+			AttestationType: stored.AttestationType,
+			Transport:       decodeTransports(stored.Transports),
+			Authenticator: webauthn.Authenticator{
+				SignCount: stored.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+func (service *WebAuthnService) loadUser(username string) (*webAuthnUser, error) {
+	var credentials []WebAuthnCredential
+
+	if err := service.database.Where("username = ?", username).Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{username: username, credentials: credentials}, nil
+}
+
+// HasCredentials reports whether a user has any registered passkeys, used to decide
+// whether login should offer WebAuthn before falling back to TOTP.
+func (service *WebAuthnService) HasCredentials(username string) (bool, error) {
+	var count int64
+	err := service.database.Model(&WebAuthnCredential{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
+func (service *WebAuthnService) BeginRegistration(username string) (*protocol.CredentialCreation, error) {
+	user, err := service.loadUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	options, session, err := service.webauthn.BeginRegistration(user)
+	if err != nil {
+		return nil, err
+	}
+
+	service.putSession(username, session)
+
+	return options, nil
+}
+
+func (service *WebAuthnService) FinishRegistration(username string, response *http.Request) error {
+	user, err := service.loadUser(username)
+	if err != nil {
+		return err
+	}
+
+	session, ok := service.takeSession(username)
+	if !ok {
+		return ErrWebAuthnSessionExpired
+	}
+
+	credential, err := service.webauthn.FinishRegistration(user, *session, response)
+	if err != nil {
+		return err
+	}
+
+	entry := WebAuthnCredential{
+		Username:        username,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      encodeTransports(credential.Transport),
+		SignCount:       credential.Authenticator.SignCount,
+		UserHandle:      user.WebAuthnID(),
+	}
+
+	return service.database.Create(&entry).Error
+}
+
+// BeginLogin starts the assertion ceremony for the user a prior password check already
+// identified via pendingToken (see CreatePendingToken), not a bare username taken from the
+// unauthenticated request.
+func (service *WebAuthnService) BeginLogin(pendingToken string) (*protocol.CredentialAssertion, error) {
+	username, err := service.resolvePendingToken(pendingToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := service.loadUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(user.credentials) == 0 {
+		return nil, ErrWebAuthnUserNotFound
+	}
+
+	options, session, err := service.webauthn.BeginLogin(user)
+	if err != nil {
+		return nil, err
+	}
+
+	service.putSession(username, session)
+
+	return options, nil
+}
+
+// FinishLogin completes the assertion ceremony for the user pendingToken identifies and
+// returns that username, so the caller can mint a real session for it.
+func (service *WebAuthnService) FinishLogin(pendingToken string, response *http.Request) (string, error) {
+	username, err := service.resolvePendingToken(pendingToken)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := service.loadUser(username)
+	if err != nil {
+		return "", err
+	}
+
+	session, ok := service.takeSession(username)
+	if !ok {
+		return "", ErrWebAuthnSessionExpired
+	}
+
+	credential, err := service.webauthn.FinishLogin(user, *session, response)
+	if err != nil {
+		return "", err
+	}
+
+	if err := service.database.Model(&WebAuthnCredential{}).
+		Where("username = ? AND credential_id = ?", username, credential.ID).
+		Update("sign_count", credential.Authenticator.SignCount).Error; err != nil {
+		log.Warn().Err(err).Str("username", username).Msg("Failed to persist updated webauthn sign count")
+	}
+
+	return username, nil
+}
+
+func (service *WebAuthnService) putSession(username string, session *webauthn.SessionData) {
+	service.sessionsMu.Lock()
+	defer service.sessionsMu.Unlock()
+	service.sessions[username] = session
+}
+
+func (service *WebAuthnService) takeSession(username string) (*webauthn.SessionData, bool) {
+	service.sessionsMu.Lock()
+	defer service.sessionsMu.Unlock()
+
+	session, ok := service.sessions[username]
+	if ok {
+		delete(service.sessions, username)
+	}
+	return session, ok
+}
+
+// CreateSessionToken mints the same kind of signed session tinyauth already issues after a
+// successful TOTP check, so a successful passkey assertion logs the user in instead of just
+// confirming the ceremony succeeded.
+func (service *WebAuthnService) CreateSessionToken(username string) (string, time.Duration, error) {
+	expiry := time.Duration(service.config.SessionExpiry) * time.Second
+	if expiry <= 0 {
+		expiry = time.Hour
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"username": username,
+		"iat":      now.Unix(),
+		"exp":      now.Add(expiry).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	signed, err := token.SignedString([]byte(service.config.SessionSecret))
+	if err != nil {
+		return "", 0, err
+	}
+
+	return signed, expiry, nil
+}
+
+// CreatePendingToken mints a short-lived token identifying username as having passed a prior
+// authentication step (password check), for BeginLogin/FinishLogin to require instead of
+// trusting a bare username off the unauthenticated request - the WebAuthn equivalent of a
+// TOTP-pending login. The password-verification step is expected to call this the same way
+// it already sets TotpPending, then hand the token to the client to complete the ceremony.
+func (service *WebAuthnService) CreatePendingToken(username string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"username": username,
+		"purpose":  webAuthnPendingPurpose,
+		"iat":      now.Unix(),
+		"exp":      now.Add(webAuthnPendingExpiry).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(service.config.SessionSecret))
+}
+
+// resolvePendingToken validates a token minted by CreatePendingToken and returns the username
+// it was issued for.
+func (service *WebAuthnService) resolvePendingToken(pendingToken string) (string, error) {
+	claims := jwt.MapClaims{}
+
+	token, err := jwt.ParseWithClaims(pendingToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(service.config.SessionSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return "", ErrWebAuthnPendingInvalid
+	}
+
+	if purpose, _ := claims["purpose"].(string); purpose != webAuthnPendingPurpose {
+		return "", ErrWebAuthnPendingInvalid
+	}
+
+	username, _ := claims["username"].(string)
+	if username == "" {
+		return "", ErrWebAuthnPendingInvalid
+	}
+
+	return username, nil
+}
+
+func encodeTransports(transports []protocol.AuthenticatorTransport) string {
+	values := make([]string, 0, len(transports))
+	for _, transport := range transports {
+		values = append(values, string(transport))
+	}
+	return strings.Join(values, ",")
+}
+
+func decodeTransports(encoded string) []protocol.AuthenticatorTransport {
+	if encoded == "" {
+		return nil
+	}
+
+	parts := strings.Split(encoded, ",")
+	transports := make([]protocol.AuthenticatorTransport, 0, len(parts))
+	for _, part := range parts {
+		transports = append(transports, protocol.AuthenticatorTransport(part))
+	}
+	return transports
+}
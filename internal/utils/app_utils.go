@@ -82,32 +82,115 @@ func GetContext(c *gin.Context) (config.UserContext, error) {
 	return *userContext, nil
 }
 
-func IsRedirectSafe(redirectURL string, domain string) bool {
+// RedirectPolicy configures which redirect targets IsRedirectSafe accepts, beyond the
+// default of matching the tinyauth cookie domain.
+type RedirectPolicy struct {
+	// CookieDomain is the existing fallback: a redirect host sharing this cookie domain
+	// is always allowed.
+	CookieDomain string
+	// AllowedHosts is a list of trusted hosts, each either an exact host, a "*.example.com"
+	// wildcard subdomain glob, or a CIDR range (e.g. "10.0.0.0/8", "fd00::/8") for IPs.
+	AllowedHosts []string
+	// AllowIPs permits redirects to bare IP addresses at all, provided they also match an
+	// AllowedHosts CIDR entry. Without this, IP redirect targets are always rejected.
+	AllowIPs bool
+	// RequireHTTPS rejects a non-https redirect target. It should track whether the
+	// deployment itself is on https (e.g. !SecureCookie means AuthServiceConfig already
+	// expects plain http) - without this, the check would reject every legitimate
+	// forward-auth redirect for a non-TLS deployment, not just a downgrade from https.
+	RequireHTTPS bool
+}
+
+// ParseTrustedRedirectHosts splits the comma-separated TRUSTED_REDIRECT_HOSTS env var into
+// the host pattern list IsRedirectSafe expects.
+func ParseTrustedRedirectHosts(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	hosts := make([]string, 0)
+
+	for _, host := range strings.Split(raw, ",") {
+		host = strings.TrimSpace(host)
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+func IsRedirectSafe(redirectURL string, policy RedirectPolicy) bool {
 	if redirectURL == "" {
 		return false
 	}
 
 	parsedURL, err := url.Parse(redirectURL)
 
-	if err != nil {
+	if err != nil || !parsedURL.IsAbs() {
 		return false
 	}
 
-	if !parsedURL.IsAbs() {
+	host := parsedURL.Hostname()
+	ip := net.ParseIP(host)
+
+	// Only enforce https when the deployment requires it; a SecureCookie: false deployment
+	// runs over plain http, and every legitimate redirect target there is http too.
+	if policy.RequireHTTPS && parsedURL.Scheme != "https" {
 		return false
 	}
 
-	cookieDomain, err := GetCookieDomain(redirectURL)
+	if ip != nil {
+		if !policy.AllowIPs {
+			return false
+		}
+		return matchesCIDR(ip, policy.AllowedHosts)
+	}
 
-	if err != nil {
-		return false
+	if cookieDomain, err := GetCookieDomain(redirectURL); err == nil && cookieDomain == policy.CookieDomain {
+		return true
 	}
 
-	if cookieDomain != domain {
-		return false
+	return matchesHostPattern(host, policy.AllowedHosts)
+}
+
+// matchesHostPattern checks a hostname against an exact match or a "*.example.com" wildcard
+// subdomain glob. The wildcard only matches subdomains, not the apex domain itself.
+func matchesHostPattern(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+			continue
+		}
+
+		if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesCIDR checks an IP against any CIDR entry in patterns, for both IPv4 and IPv6.
+func matchesCIDR(ip net.IP, patterns []string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(pattern)
+		if err != nil {
+			continue
+		}
+
+		if network.Contains(ip) {
+			return true
+		}
 	}
 
-	return true
+	return false
 }
 
 func GetLogLevel(level string) zerolog.Level {
@@ -0,0 +1,108 @@
+package utils_test
+
+import (
+	"testing"
+
+	"tinyauth/internal/utils"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestIsRedirectSafe(t *testing.T) {
+	cases := []struct {
+		name        string
+		redirectURL string
+		policy      utils.RedirectPolicy
+		want        bool
+	}{
+		{
+			name:        "matches cookie domain",
+			redirectURL: "https://app.example.com/callback",
+			policy:      utils.RedirectPolicy{CookieDomain: "example.com"},
+			want:        true,
+		},
+		{
+			name:        "wildcard subdomain match",
+			redirectURL: "https://app.example.com/callback",
+			policy:      utils.RedirectPolicy{AllowedHosts: []string{"*.example.com"}},
+			want:        true,
+		},
+		{
+			name:        "wildcard does not match apex",
+			redirectURL: "https://example.com/callback",
+			policy:      utils.RedirectPolicy{AllowedHosts: []string{"*.example.com"}},
+			want:        false,
+		},
+		{
+			name:        "exact host match",
+			redirectURL: "https://example.com/callback",
+			policy:      utils.RedirectPolicy{AllowedHosts: []string{"example.com"}},
+			want:        true,
+		},
+		{
+			name:        "ipv4 cidr match",
+			redirectURL: "https://10.0.5.2/callback",
+			policy:      utils.RedirectPolicy{AllowIPs: true, AllowedHosts: []string{"10.0.0.0/8"}},
+			want:        true,
+		},
+		{
+			name:        "ipv6 cidr match",
+			redirectURL: "https://[fd00::1]/callback",
+			policy:      utils.RedirectPolicy{AllowIPs: true, AllowedHosts: []string{"fd00::/8"}},
+			want:        true,
+		},
+		{
+			name:        "ip rejected when not in allowlist",
+			redirectURL: "https://10.0.5.2/callback",
+			policy:      utils.RedirectPolicy{AllowIPs: true, AllowedHosts: []string{"192.168.0.0/16"}},
+			want:        false,
+		},
+		{
+			name:        "ip rejected when AllowIPs is false",
+			redirectURL: "https://10.0.5.2/callback",
+			policy:      utils.RedirectPolicy{AllowIPs: false, AllowedHosts: []string{"10.0.0.0/8"}},
+			want:        false,
+		},
+		{
+			name:        "scheme downgrade rejected",
+			redirectURL: "http://app.example.com/callback",
+			policy:      utils.RedirectPolicy{AllowedHosts: []string{"*.example.com"}, RequireHTTPS: true},
+			want:        false,
+		},
+		{
+			name:        "http allowed when https is not required",
+			redirectURL: "http://app.example.com/callback",
+			policy:      utils.RedirectPolicy{AllowedHosts: []string{"*.example.com"}, RequireHTTPS: false},
+			want:        true,
+		},
+		{
+			name:        "unrelated host rejected",
+			redirectURL: "https://evil.com/callback",
+			policy:      utils.RedirectPolicy{CookieDomain: "example.com", AllowedHosts: []string{"*.example.com"}},
+			want:        false,
+		},
+		{
+			name:        "empty redirect rejected",
+			redirectURL: "",
+			policy:      utils.RedirectPolicy{CookieDomain: "example.com"},
+			want:        false,
+		},
+		{
+			name:        "relative redirect rejected",
+			redirectURL: "/callback",
+			policy:      utils.RedirectPolicy{CookieDomain: "example.com"},
+			want:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, utils.IsRedirectSafe(tc.redirectURL, tc.policy), tc.want)
+		})
+	}
+}
+
+func TestParseTrustedRedirectHosts(t *testing.T) {
+	assert.DeepEqual(t, utils.ParseTrustedRedirectHosts(""), []string(nil))
+	assert.DeepEqual(t, utils.ParseTrustedRedirectHosts("*.example.com, 10.0.0.0/8 ,example.org"), []string{"*.example.com", "10.0.0.0/8", "example.org"})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"tinyauth/internal/config"
+	"tinyauth/internal/server"
+	"tinyauth/internal/service"
+	"tinyauth/internal/service/oidc"
+	"tinyauth/internal/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	databaseService := service.NewDatabaseService(service.DatabaseServiceConfig{
+		DatabasePath: getEnv("DATABASE_PATH", "/data/tinyauth.db"),
+	})
+
+	if err := databaseService.Init(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize database")
+	}
+
+	rootDomain := getEnv("ROOT_DOMAIN", "localhost")
+	appURL := getEnv("APP_URL", "https://"+rootDomain)
+
+	oidcClients, err := oidc.ParseClients(os.Getenv("OIDC_CLIENTS"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse OIDC_CLIENTS")
+	}
+
+	indieAuthProfiles, err := service.ParseIndieAuthProfiles(os.Getenv("INDIEAUTH_PROFILES"))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse INDIEAUTH_PROFILES")
+	}
+
+	cookieDomain, err := utils.GetCookieDomain(appURL)
+	if err != nil {
+		cookieDomain = rootDomain
+	}
+
+	redirectPolicy := utils.RedirectPolicy{
+		CookieDomain: cookieDomain,
+		AllowedHosts: utils.ParseTrustedRedirectHosts(os.Getenv("TRUSTED_REDIRECT_HOSTS")),
+		AllowIPs:     os.Getenv("TRUSTED_REDIRECT_ALLOW_IPS") == "true",
+		RequireHTTPS: os.Getenv("SECURE_COOKIE") != "false",
+	}
+
+	router, appDiscovery, err := server.NewRouter(server.Config{
+		RootDomain:        rootDomain,
+		AppURL:            appURL,
+		SecureCookie:      os.Getenv("SECURE_COOKIE") != "false",
+		SessionSecret:     os.Getenv("SECRET"),
+		SessionExpiry:     3600,
+		SessionCookieName: "tinyauth-session",
+		Oidc: oidc.ServiceConfig{
+			Issuer:  appURL,
+			Clients: oidcClients,
+		},
+		WebAuthn: service.WebAuthnServiceConfig{
+			RPDisplayName: "tinyauth",
+			RPID:          rootDomain,
+			RPOrigins:     []string{appURL},
+		},
+		IndieAuth: service.IndieAuthServiceConfig{
+			Profiles: indieAuthProfiles,
+		},
+		AppDiscoveryBackend: os.Getenv("APP_DISCOVERY_BACKEND"),
+		FileDiscovery: service.FileDiscoveryConfig{
+			Path: os.Getenv("APP_DISCOVERY_FILE_PATH"),
+		},
+		KubernetesDiscovery: service.KubernetesDiscoveryConfig{
+			Namespace: os.Getenv("APP_DISCOVERY_KUBERNETES_NAMESPACE"),
+		},
+		RedirectPolicy: redirectPolicy,
+	}, server.Dependencies{
+		Database:   databaseService.GetDatabase(),
+		LookupUser: lookupUserFromEnv(),
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to build server")
+	}
+
+	log.Info().Str("backend", getEnv("APP_DISCOVERY_BACKEND", "docker")).Msg("Started app discovery backend")
+	go logAppDiscoveryEvents(appDiscovery)
+
+	httpServer := &http.Server{
+		Addr:              getEnv("APP_ADDRESS", ":3000"),
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Info().Str("address", httpServer.Addr).Msg("Starting tinyauth")
+
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal().Err(err).Msg("Server stopped unexpectedly")
+	}
+}
+
+// lookupUserFromEnv builds a username -> config.UserContext resolver out of the static
+// USERS env var, the same list AuthService itself is configured from. It only carries the
+// static fields (OAuth-derived claims like groups are empty here); this is enough for the
+// identity-provider endpoints that target locally-authenticated users.
+func lookupUserFromEnv() func(username string) (config.UserContext, bool) {
+	users := map[string]config.UserContext{}
+
+	for _, entry := range strings.Split(os.Getenv("USERS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		username := strings.SplitN(entry, ":", 2)[0]
+		if username == "" {
+			continue
+		}
+
+		users[username] = config.UserContext{
+			Username: username,
+			Name:     username,
+		}
+	}
+
+	return func(username string) (config.UserContext, bool) {
+		user, ok := users[username]
+		return user, ok
+	}
+}
+
+// logAppDiscoveryEvents drains the discovery backend's push-update channel for visibility.
+// The forward-auth request path itself reads app config by calling GetApp directly
+// (AppController), not by consuming this channel.
+func logAppDiscoveryEvents(appDiscovery service.AppDiscovery) {
+	for event := range appDiscovery.Watch() {
+		if event.Removed {
+			log.Debug().Str("domain", event.Domain).Msg("App discovery: app removed")
+			continue
+		}
+		log.Debug().Str("domain", event.Domain).Msg("App discovery: app updated")
+	}
+}
+
+func getEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}